@@ -2,7 +2,7 @@ package main
 
 import (
 	"log"
-	"github.com/tappleby/slack-auth-proxy/slack"
+	"github.com/tappleby/slack-auth-proxy/providers"
 	"net/http"
 	"fmt"
 	"net/http/httputil"
@@ -11,13 +11,41 @@ import (
 	"time"
 	"html/template"
 	"encoding/base64"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"hash"
+	"io/ioutil"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"regexp"
 )
 
+// signedHeaders is the canonical set of request headers included in the
+// GAP-Signature HMAC, in order. Mirrors the header set oauth2_proxy signs so
+// upstreams can share verification code between the two proxies.
+var signedHeaders = []string{
+	"Content-Length",
+	"Content-Md5",
+	"Content-Type",
+	"Date",
+	"Authorization",
+	"Cookie",
+	"X-Forwarded-User",
+	"X-Forwarded-Email",
+}
+
 const (
 	signInPath = "/oauth2/sign_in"
 	oauthStartPath = "/oauth2/start"
 	oauthCallbackPath = "/oauth2/callback"
+	authOnlyPath = "/oauth2/auth"
+	signOutPath = "/oauth2/sign_out"
 	staticDir = "/_slackproxy"
+	csrfCookieName = "_slackauthproxy_csrf"
+	pingPath = "/ping"
 )
 
 var (
@@ -26,21 +54,29 @@ var (
 
 type OAuthServer struct {
 	CookieKey string
-	Validator func(*slack.Auth, *UpstreamConfiguration) bool
+	Validator func(*providers.SessionState, *UpstreamConfiguration) bool
+
+	// CookieRefresh is how long a session may go unrefreshed before
+	// ServeHTTP calls the provider's refresh endpoint for a new access
+	// token rather than forcing the user through the full OAuth flow
+	// again. Zero disables refreshing.
+	CookieRefresh time.Duration
 
-	slackOauth *slack.OAuthClient
+	provider providers.Provider
 	serveMux	*http.ServeMux
 	staticHandler http.Handler
 
 	secureCookie *securecookie.SecureCookie
 	upstreamsConfig UpstreamConfigurationMap
+	skipAuthRegexes map[*UpstreamConfiguration][]*regexp.Regexp
 
 	config *Configuration
 }
 
-func NewOauthServer(slackOauth *slack.OAuthClient, config *Configuration) *OAuthServer {
+func NewOauthServer(provider providers.Provider, config *Configuration) *OAuthServer {
 	serveMux := http.NewServeMux()
 	upstreamsPathMap := make(UpstreamConfigurationMap)
+	skipAuthRegexes := make(map[*UpstreamConfiguration][]*regexp.Regexp)
 
 	for _, upstream := range config.Upstreams {
 		u := upstream.HostURL
@@ -52,9 +88,22 @@ func NewOauthServer(slackOauth *slack.OAuthClient, config *Configuration) *OAuth
 		}
 
 		log.Printf("mapping %s => %s", path, u)
-		serveMux.Handle(path, httputil.NewSingleHostReverseProxy(u))
+
+		var handler http.Handler = httputil.NewSingleHostReverseProxy(u)
+		if upstream.SigningKey != "" {
+			handler = newSigningHandler(handler, upstream.SigningKey, upstream.SigningAlgorithm)
+		}
+		serveMux.Handle(path, handler)
 
 		upstreamsPathMap[path] = upstream
+
+		for _, pattern := range upstream.SkipAuthRegex {
+			compiled, err := regexp.Compile(pattern)
+			if err != nil {
+				log.Fatalf("invalid skip_auth_regex %q for %s: %s", pattern, path, err)
+			}
+			skipAuthRegexes[upstream] = append(skipAuthRegexes[upstream], compiled)
+		}
 	}
 
 	decode64 := func(name, s string) []byte {
@@ -74,15 +123,64 @@ func NewOauthServer(slackOauth *slack.OAuthClient, config *Configuration) *OAuth
 	return &OAuthServer{
 		CookieKey: "_slackauthproxy",
 		Validator: NewValidator(),
+		CookieRefresh: config.CookieRefresh,
 		serveMux: serveMux,
-		slackOauth: slackOauth,
+		provider: provider,
 		upstreamsConfig: upstreamsPathMap,
+		skipAuthRegexes: skipAuthRegexes,
 		secureCookie: secureCookie,
 		staticHandler: http.FileServer(http.Dir("static")),
 		config: config,
 	}
 }
 
+// newSigningHandler wraps handler so that every request forwarded to it
+// carries a GAP-Signature header, letting the upstream verify the request
+// really came from this proxy. It signs unconditionally; NewOauthServer
+// only wraps upstreams that configure a key, so requests to the others are
+// left untouched.
+func newSigningHandler(handler http.Handler, key string, algorithm string) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		signRequest(req, key, algorithm)
+		handler.ServeHTTP(rw, req)
+	})
+}
+
+// signRequest computes an HMAC over signedHeaders plus the request URI and
+// body, and sets it as the GAP-Signature header in the form
+// "<algorithm> <base64(hmac)>". The request body is fully buffered so it can
+// be hashed and then replayed to the upstream.
+func signRequest(req *http.Request, key string, algorithm string) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = ioutil.ReadAll(req.Body)
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	if algorithm == "" {
+		algorithm = "sha1"
+	}
+
+	mac := hmac.New(hashFuncForAlgorithm(algorithm), []byte(key))
+	for _, header := range signedHeaders {
+		mac.Write([]byte(req.Header.Get(header)))
+	}
+	mac.Write([]byte(req.URL.RequestURI()))
+	mac.Write(body)
+
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	req.Header.Set("GAP-Signature", fmt.Sprintf("%s %s", algorithm, signature))
+}
+
+func hashFuncForAlgorithm(algorithm string) func() hash.Hash {
+	switch algorithm {
+	case "sha256":
+		return sha256.New
+	default:
+		return sha1.New
+	}
+}
+
 func (s *OAuthServer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	var ok bool
 
@@ -104,34 +202,45 @@ func (s *OAuthServer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	} else if (reqPath == oauthCallbackPath) {
 		s.handleOAuthCallback(rw, req)
 		return
+	} else if (reqPath == authOnlyPath) {
+		s.handleAuthOnly(rw, req)
+		return
+	} else if (reqPath == signOutPath) {
+		s.handleSignOut(rw, req)
+		return
+	} else if (reqPath == pingPath) {
+		rw.WriteHeader(http.StatusOK)
+		return
 	} else if (strings.HasPrefix(reqPath, staticDir)) {
 		req.URL.Path = strings.Replace(reqPath, staticDir, "", 1)
 		s.staticHandler.ServeHTTP(rw, req);
 		return;
 	}
 
-	handler, pattern := s.serveMux.Handler(req)
- 	upstreamConfig := s.upstreamsConfig[pattern]
+	handler, upstreamConfig := s.resolveUpstream(reqPath)
 
 	if upstreamConfig == nil {
-		pattern = strings.TrimPrefix(pattern, "/")
-		upstreamConfig = s.upstreamsConfig[pattern]
+		http.NotFound(rw, req)
+		return
 	}
 
-	if upstreamConfig == nil {
-		http.NotFound(rw, req)
+	if s.skipsAuth(upstreamConfig, reqPath) {
+		handler.ServeHTTP(rw, req)
 		return
 	}
 
-	if !ok {
-		cookie, _ := req.Cookie(s.CookieKey)
+	session, valid := s.getCookieSession(req)
 
-		if cookie != nil {
-			auth := new(slack.Auth)
-			s.secureCookie.Decode(s.CookieKey, cookie.Value, &auth);
+	if valid && s.needsRefresh(session) {
+		session, valid = s.refreshSession(rw, req, session)
+	}
 
-			ok = s.Validator(auth, upstreamConfig)
-		}
+	if valid && !s.provider.ValidateSession(session) {
+		valid = false
+	}
+
+	if !ok {
+		ok = valid && s.Validator(session, upstreamConfig)
 	}
 
 	if !ok {
@@ -140,9 +249,164 @@ func (s *OAuthServer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	s.addUpstreamHeaders(req, upstreamConfig, session)
+
 	handler.ServeHTTP(rw, req)
 }
 
+// resolveUpstream looks up the proxy handler and UpstreamConfiguration that
+// serveMux would route path to, without actually dispatching anything.
+// ServeHTTP uses it for the request it's proxying; handleAuthOnly uses it
+// for the path nginx is asking about via X-Original-URI, which is never the
+// request path of the /oauth2/auth subrequest itself.
+func (s *OAuthServer) resolveUpstream(path string) (http.Handler, *UpstreamConfiguration) {
+	handler, pattern := s.serveMux.Handler(&http.Request{URL: &url.URL{Path: path}})
+	upstreamConfig := s.upstreamsConfig[pattern]
+
+	if upstreamConfig == nil {
+		pattern = strings.TrimPrefix(pattern, "/")
+		upstreamConfig = s.upstreamsConfig[pattern]
+	}
+
+	return handler, upstreamConfig
+}
+
+// addUpstreamHeaders mutates req, before it's proxied, with the
+// conventional headers downstream apps (dashboards, Grafana, Kibana, etc.)
+// already know how to consume, so they work behind the proxy unmodified.
+// When PassBasicAuth is enabled it also sets a Basic Authorization header
+// using the session's username and the upstream's configured password.
+func (s *OAuthServer) addUpstreamHeaders(req *http.Request, upstreamConfig *UpstreamConfiguration, session *providers.SessionState) {
+	username := s.sessionUsername(session)
+
+	if upstreamConfig.PassUserHeaders {
+		req.Header.Set("X-Forwarded-User", username)
+		req.Header.Set("X-Forwarded-Email", s.sessionEmail(session))
+		req.Header.Set("X-Forwarded-Team", session.Team)
+	}
+
+	if upstreamConfig.PassBasicAuth {
+		req.SetBasicAuth(username, upstreamConfig.BasicAuthPassword)
+	}
+}
+
+// sessionUsername and sessionEmail go through the provider rather than
+// reading session.User/session.Email directly, since GetUsername/
+// GetEmailAddress are the interface's way to derive them and a
+// provider may need to do more than a field read (e.g. a fresh lookup).
+func (s *OAuthServer) sessionUsername(session *providers.SessionState) string {
+	username, err := s.provider.GetUsername(session)
+	if err != nil {
+		log.Printf("error getting username: %s", err.Error())
+		return ""
+	}
+	return username
+}
+
+func (s *OAuthServer) sessionEmail(session *providers.SessionState) string {
+	email, err := s.provider.GetEmailAddress(session)
+	if err != nil {
+		log.Printf("error getting email address: %s", err.Error())
+		return ""
+	}
+	return email
+}
+
+// getCookieSession decodes the session cookie on the request, if present.
+// The second return value indicates whether a cookie was found and
+// successfully decoded, not whether the resulting session is valid against
+// any upstream.
+func (s *OAuthServer) getCookieSession(req *http.Request) (*providers.SessionState, bool) {
+	cookie, _ := req.Cookie(s.CookieKey)
+
+	if cookie == nil {
+		return nil, false
+	}
+
+	session := new(providers.SessionState)
+	if err := s.secureCookie.Decode(s.CookieKey, cookie.Value, &session); err != nil {
+		return nil, false
+	}
+
+	return session, true
+}
+
+// skipsAuth reports whether path matches one of upstreamConfig's
+// skip_auth_regex patterns, meaning the request should be forwarded without
+// ever looking at the session cookie. This is for unauthenticated webhook,
+// metrics, or health-check endpoints that live on the same host as an
+// authenticated UI.
+func (s *OAuthServer) skipsAuth(upstreamConfig *UpstreamConfiguration, path string) bool {
+	for _, re := range s.skipAuthRegexes[upstreamConfig] {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// needsRefresh reports whether session is old enough that it should be
+// refreshed with the provider before being trusted again.
+func (s *OAuthServer) needsRefresh(session *providers.SessionState) bool {
+	return s.CookieRefresh > 0 && time.Now().Sub(session.CreatedAt) > s.CookieRefresh
+}
+
+// refreshSession asks the provider for a new access token and, on success,
+// re-encodes and re-sets the cookie so the browser picks up the refreshed
+// session without a full OAuth round trip. On failure the caller falls
+// through to handleSignIn same as an invalid cookie would.
+func (s *OAuthServer) refreshSession(rw http.ResponseWriter, req *http.Request, session *providers.SessionState) (*providers.SessionState, bool) {
+	refreshed, err := s.provider.Refresh(session)
+	if err != nil {
+		log.Printf("error refreshing session for %s: %s", session.User, err.Error())
+		return nil, false
+	}
+
+	encoded, err := s.secureCookie.Encode(s.CookieKey, refreshed)
+	if err != nil {
+		log.Printf("Error encoding cookie %s", err.Error())
+		return nil, false
+	}
+
+	s.SetCookie(rw, req, encoded)
+	return refreshed, true
+}
+
+// handleAuthOnly validates the session cookie without proxying or
+// redirecting, for use as an nginx auth_request subrequest target. nginx is
+// expected to set X-Original-URI to the URI of the request being
+// authenticated (auth_request_set or proxy_set_header in the protected
+// location), so the Validator call here is gated by that upstream's own
+// rules rather than some default. On success it returns 202 and populates
+// X-Auth-Request-* headers so nginx can forward them to the real upstream
+// via auth_request_set. On failure it returns 401, leaving the decision of
+// where to send the user (e.g. to oauthStartPath) to nginx's error_page
+// configuration.
+func (s *OAuthServer) handleAuthOnly(rw http.ResponseWriter, req *http.Request) {
+	originalPath := req.Header.Get("X-Original-URI")
+	if u, err := url.Parse(originalPath); err == nil {
+		originalPath = u.Path
+	}
+
+	_, upstreamConfig := s.resolveUpstream(originalPath)
+	if upstreamConfig == nil {
+		rw.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	session, ok := s.getCookieSession(req)
+
+	if !ok || !s.provider.ValidateSession(session) || !s.Validator(session, upstreamConfig) {
+		rw.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	rw.Header().Set("X-Auth-Request-User", s.sessionUsername(session))
+	rw.Header().Set("X-Auth-Request-Email", s.sessionEmail(session))
+	rw.Header().Set("X-Auth-Request-Team", session.Team)
+	rw.WriteHeader(http.StatusAccepted)
+}
+
 func (s *OAuthServer) GetRedirect(req *http.Request) (string, error) {
 	err := req.ParseForm()
 
@@ -165,14 +429,135 @@ func (s *OAuthServer) handleSignIn(rw http.ResponseWriter, req *http.Request) {
 	t := struct {
 		Title string
 		Redirect string
+		SignOutPath string
 	}{
 		Title: "Sign in",
 		Redirect: req.URL.RequestURI(),
+		SignOutPath: signOutPath,
 	}
 
 	s.renderTemplate(rw, "sign_in", t)
 }
 
+// handleSignOut ends the caller's session: it revokes the access token with
+// the provider so a stolen cookie can't keep being replayed after the user
+// signs out, clears the session cookie locally, and sends the browser on to
+// SignOutRedirect (default "/"). Revocation failures are logged but don't
+// block the local sign-out, since the cookie is what actually gates access
+// to upstreams.
+func (s *OAuthServer) handleSignOut(rw http.ResponseWriter, req *http.Request) {
+	if session, ok := s.getCookieSession(req); ok {
+		if err := s.provider.Revoke(session); err != nil {
+			log.Printf("error revoking session for %s: %s", session.User, err.Error())
+		}
+	}
+
+	s.ClearCookie(rw, req)
+
+	redirect := s.config.SignOutRedirect
+	if redirect == "" {
+		redirect = "/"
+	}
+
+	http.Redirect(rw, req, redirect, 302)
+}
+
+// csrfState is the payload stored in the CSRF cookie across the OAuth
+// redirect: a nonce the callback must see echoed back as the `state`
+// parameter, and the redirect the user actually asked for (since `state`
+// itself is no longer trusted to carry it).
+type csrfState struct {
+	Nonce    string
+	Redirect string
+}
+
+func makeNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (s *OAuthServer) setCSRFCookie(rw http.ResponseWriter, req *http.Request, state *csrfState) error {
+	encoded, err := s.secureCookie.Encode(csrfCookieName, state)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(rw, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    encoded,
+		Path:     "/",
+		Domain:   s.getCookieDomain(req),
+		Expires:  time.Now().Add(time.Duration(5) * time.Minute),
+		HttpOnly: true,
+		Secure:   s.isCookieSecure(req),
+	})
+	return nil
+}
+
+func (s *OAuthServer) getCSRFState(req *http.Request) (*csrfState, bool) {
+	cookie, _ := req.Cookie(csrfCookieName)
+	if cookie == nil {
+		return nil, false
+	}
+
+	state := new(csrfState)
+	if err := s.secureCookie.Decode(csrfCookieName, cookie.Value, &state); err != nil {
+		return nil, false
+	}
+
+	return state, true
+}
+
+func (s *OAuthServer) clearCSRFCookie(rw http.ResponseWriter, req *http.Request) {
+	http.SetCookie(rw, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    "",
+		Path:     "/",
+		Domain:   s.getCookieDomain(req),
+		Expires:  time.Now().Add(time.Duration(1) * time.Hour * -1),
+		HttpOnly: true,
+		Secure:   s.isCookieSecure(req),
+	})
+}
+
+// isValidRedirect only allows following a post-login redirect that is
+// either a plain relative path (never "//host/path", which browsers treat
+// as protocol-relative and would hand the redirect to an attacker's host)
+// or explicitly allow-listed in config.
+func isValidRedirect(redirect string, allowedHosts []string) bool {
+	if redirect == "" {
+		return false
+	}
+
+	u, err := url.Parse(redirect)
+	if err != nil {
+		return false
+	}
+
+	if u.Host == "" {
+		// Browsers normalize a leading "/\" to "//" in the Location header,
+		// turning what url.Parse sees as a plain path into a scheme-relative
+		// redirect to an attacker's host, same as a literal "//" would.
+		return strings.HasPrefix(redirect, "/") &&
+			!strings.HasPrefix(redirect, "//") &&
+			!strings.HasPrefix(redirect, "/\\")
+	}
+
+	if u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+
+	for _, host := range allowedHosts {
+		if u.Host == host {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *OAuthServer) handleOAuthStart(rw http.ResponseWriter, req *http.Request) {
 	redirect, err := s.GetRedirect(req)
 	if err != nil {
@@ -180,7 +565,18 @@ func (s *OAuthServer) handleOAuthStart(rw http.ResponseWriter, req *http.Request
 		return
 	}
 
-	http.Redirect(rw, req, s.slackOauth.LoginUrl(redirect).String(), 302)
+	nonce, err := makeNonce()
+	if err != nil {
+		s.ErrorPage(rw, 500, "Internal Error", err.Error())
+		return
+	}
+
+	if err := s.setCSRFCookie(rw, req, &csrfState{Nonce: nonce, Redirect: redirect}); err != nil {
+		s.ErrorPage(rw, 500, "Internal Error", err.Error())
+		return
+	}
+
+	http.Redirect(rw, req, s.provider.LoginURL(nonce).String(), 302)
 }
 
 func (s *OAuthServer) handleOAuthCallback(rw http.ResponseWriter, req *http.Request) {
@@ -196,16 +592,15 @@ func (s *OAuthServer) handleOAuthCallback(rw http.ResponseWriter, req *http.Requ
 		return
 	}
 
-	access, err := s.slackOauth.RedeemCode(req.Form.Get("code"))
+	csrf, ok := s.getCSRFState(req)
+	s.clearCSRFCookie(rw, req)
 
-	if err != nil {
-		log.Printf("error redeeming code %s", err.Error())
-		s.ErrorPage(rw, 500, "Internal Error", err.Error())
+	if !ok || req.Form.Get("state") != csrf.Nonce {
+		s.ErrorPage(rw, 403, "Permission Denied", "oauth state did not match")
 		return
 	}
 
-	cl := slack.NewClient(access.Token)
-	auth, err := cl.Auth.Test()
+	session, err := s.provider.Redeem(req.Form.Get("code"))
 
 	if err != nil {
 		log.Printf("error redeeming code %s", err.Error())
@@ -213,19 +608,19 @@ func (s *OAuthServer) handleOAuthCallback(rw http.ResponseWriter, req *http.Requ
 		return
 	}
 
-	encoded, err := s.secureCookie.Encode(s.CookieKey, auth)
+	encoded, err := s.secureCookie.Encode(s.CookieKey, session)
 
 	if err != nil {
 		log.Printf("Error encoding cookie %s", err.Error())
 		s.ErrorPage(rw, 500, "Internal Error", "Error encoding auth cookie")
 	}
 
-	redirect := req.Form.Get("state")
-	if redirect == "" {
+	redirect := csrf.Redirect
+	if !isValidRedirect(redirect, s.config.RedirectAllowedHosts) {
 		redirect = "/"
 	}
 
-	log.Printf("authenticating %s completed", auth.Username)
+	log.Printf("authenticating %s completed", session.User)
 
 	s.SetCookie(rw, req, encoded)
 	http.Redirect(rw, req, redirect, 302)
@@ -245,15 +640,22 @@ func (s *OAuthServer) ErrorPage(rw http.ResponseWriter, code int, title string,
 	s.renderTemplate(rw, "error", t)
 }
 
+const defaultCookieExpire = time.Duration(168) * time.Hour // 7 days
+
 func (s *OAuthServer) SetCookie(rw http.ResponseWriter, req *http.Request, val string) {
+	expire := s.config.CookieExpire
+	if expire == 0 {
+		expire = defaultCookieExpire
+	}
+
 	cookie := &http.Cookie{
 		Name:     s.CookieKey,
 		Value:   val,
 		Path:     "/",
 		Domain:   s.getCookieDomain(req),
-		Expires:  time.Now().Add(time.Duration(168) * time.Hour), // 7 days
+		Expires:  time.Now().Add(expire),
 		HttpOnly: true,
-		// Secure: req. ... ? set if X-Scheme: https ?
+		Secure:   s.isCookieSecure(req),
 	}
 
 	http.SetCookie(rw, cookie)
@@ -267,10 +669,41 @@ func (s *OAuthServer) ClearCookie(rw http.ResponseWriter, req *http.Request) {
 		Domain:   s.getCookieDomain(req),
 		Expires:  time.Now().Add(time.Duration(1) * time.Hour * -1),
 		HttpOnly: true,
+		Secure:   s.isCookieSecure(req),
 	}
 	http.SetCookie(rw, cookie)
 }
 
+// isCookieSecure decides whether the Secure flag should be set on the
+// session cookie. CookieSecure in config can force this one way or the
+// other ("always"/"never") for operators who know their topology; anything
+// else ("auto", or left unset) infers it from how this request arrived.
+func (s *OAuthServer) isCookieSecure(req *http.Request) bool {
+	switch s.config.CookieSecure {
+	case "always":
+		return true
+	case "never":
+		return false
+	default:
+		return isRequestSecure(req)
+	}
+}
+
+// isRequestSecure reports whether req reached us over HTTPS, either
+// directly or as reported by a TLS-terminating proxy in front of us.
+func isRequestSecure(req *http.Request) bool {
+	if req.TLS != nil {
+		return true
+	}
+	if req.Header.Get("X-Forwarded-Proto") == "https" {
+		return true
+	}
+	if req.Header.Get("X-Scheme") == "https" {
+		return true
+	}
+	return false
+}
+
 func (s *OAuthServer) renderTemplate(rw http.ResponseWriter, tmpl string, data interface {}) {
 	err := oauthTemplates.ExecuteTemplate(rw, tmpl+".html", data)
 	if err != nil {
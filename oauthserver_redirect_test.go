@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestIsValidRedirect(t *testing.T) {
+	allowedHosts := []string{"allowed.example.com"}
+
+	cases := []struct {
+		name     string
+		redirect string
+		want     bool
+	}{
+		{"empty", "", false},
+		{"relative path", "/dashboard", true},
+		{"relative path with query", "/dashboard?x=1", true},
+		{"protocol-relative", "//evil.com", false},
+		{"backslash bypass", "/\\evil.com", false},
+		{"backslash bypass, more slashes", "/\\/evil.com", false},
+		{"allowed absolute http", "http://allowed.example.com/x", true},
+		{"allowed absolute https", "https://allowed.example.com/x", true},
+		{"disallowed host", "http://evil.com/x", false},
+		{"non-http(s) scheme, allowed host", "javascript://allowed.example.com/x", false},
+		{"data scheme", "data:text/html,whatever", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := isValidRedirect(c.redirect, allowedHosts); got != c.want {
+				t.Errorf("isValidRedirect(%q) = %v, want %v", c.redirect, got, c.want)
+			}
+		})
+	}
+}
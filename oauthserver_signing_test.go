@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestSignRequest(t *testing.T) {
+	cases := []struct {
+		name      string
+		algorithm string
+	}{
+		{"default algorithm (sha1)", ""},
+		{"sha1", "sha1"},
+		{"sha256", "sha256"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			body := []byte("payload")
+			req, err := http.NewRequest("POST", "http://upstream.example.com/path?a=1", bytes.NewReader(body))
+			if err != nil {
+				t.Fatalf("NewRequest: %s", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Cookie", "_slackauthproxy=abc")
+
+			key := "shared-secret"
+			signRequest(req, key, c.algorithm)
+
+			wantAlgorithm := c.algorithm
+			if wantAlgorithm == "" {
+				wantAlgorithm = "sha1"
+			}
+
+			mac := hmac.New(hashFuncForAlgorithm(wantAlgorithm), []byte(key))
+			for _, header := range signedHeaders {
+				mac.Write([]byte(req.Header.Get(header)))
+			}
+			mac.Write([]byte(req.URL.RequestURI()))
+			mac.Write(body)
+			wantSignature := wantAlgorithm + " " + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+			if got := req.Header.Get("GAP-Signature"); got != wantSignature {
+				t.Errorf("GAP-Signature = %q, want %q", got, wantSignature)
+			}
+
+			// The body must still be readable by the handler signRequest wraps.
+			replayed, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				t.Fatalf("reading replayed body: %s", err)
+			}
+			if !bytes.Equal(replayed, body) {
+				t.Errorf("replayed body = %q, want %q", replayed, body)
+			}
+		})
+	}
+}
+
+func TestHashFuncForAlgorithm(t *testing.T) {
+	if hashFuncForAlgorithm("sha256")() == nil {
+		t.Fatal("sha256 returned nil hash")
+	}
+	if hashFuncForAlgorithm("sha1")().Size() != sha1.Size {
+		t.Errorf("sha1 hash size = %d, want %d", hashFuncForAlgorithm("sha1")().Size(), sha1.Size)
+	}
+	if hashFuncForAlgorithm("sha256")().Size() != sha256.Size {
+		t.Errorf("sha256 hash size = %d, want %d", hashFuncForAlgorithm("sha256")().Size(), sha256.Size)
+	}
+	if hashFuncForAlgorithm("bogus")().Size() != sha1.Size {
+		t.Errorf("unknown algorithm should default to sha1")
+	}
+}
@@ -0,0 +1,135 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var githubAuthURL, _ = url.Parse("https://github.com/login/oauth/authorize")
+var githubTokenURL, _ = url.Parse("https://github.com/login/oauth/access_token")
+var githubUserURL = "https://api.github.com/user"
+var githubOrgsURL = "https://api.github.com/user/orgs"
+var githubGrantURLFormat = "https://api.github.com/applications/%s/grant"
+
+// GithubProvider authenticates against a user's GitHub account, matching
+// upstream allow-lists on org membership. Team memberships are not looked
+// up, so Groups holds org logins only.
+type GithubProvider struct {
+	oauth2Config
+}
+
+func NewGithubProvider(clientID, clientSecret string, redirectURL *url.URL) *GithubProvider {
+	return &GithubProvider{
+		oauth2Config: oauth2Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			AuthURL:      githubAuthURL,
+			TokenURL:     githubTokenURL,
+			Scope:        "read:org user:email",
+		},
+	}
+}
+
+func (p *GithubProvider) Redeem(code string) (*SessionState, error) {
+	token, err := p.redeem(code)
+	if err != nil {
+		return nil, err
+	}
+
+	var user struct {
+		Login string `json:"login"`
+		Email string `json:"email"`
+	}
+	if err := getJSON(githubUserURL, token.AccessToken, &user); err != nil {
+		return nil, err
+	}
+
+	orgs, err := githubOrgs(token.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SessionState{
+		User:        user.Login,
+		Email:       user.Email,
+		Groups:      orgs,
+		AccessToken: token.AccessToken,
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+// githubOrgs lists the logins of every org the token's user belongs to, for
+// Validator rules that gate an upstream by org membership. This requires
+// the read:org scope GithubProvider requests above; without it GitHub
+// returns only publicly-visible orgs rather than erroring.
+func githubOrgs(accessToken string) ([]string, error) {
+	var orgs []struct {
+		Login string `json:"login"`
+	}
+	if err := getJSON(githubOrgsURL, accessToken, &orgs); err != nil {
+		return nil, err
+	}
+
+	logins := make([]string, len(orgs))
+	for i, org := range orgs {
+		logins[i] = org.Login
+	}
+	return logins, nil
+}
+
+// Refresh always fails: classic GitHub OAuth apps issue access tokens that
+// don't expire and never hand out a refresh token, so there's nothing to
+// trade in. CookieRefresh still forces a full sign-in once the window
+// elapses, same as before this feature existed.
+func (p *GithubProvider) Refresh(session *SessionState) (*SessionState, error) {
+	return nil, errNoRefreshToken
+}
+
+// Revoke deletes the app's grant for this token, which revokes every token
+// GitHub has issued to it for that user. Classic OAuth apps have no
+// endpoint to revoke a single token, only the whole grant, so that's what
+// we call here.
+func (p *GithubProvider) Revoke(session *SessionState) error {
+	body, err := json.Marshal(struct {
+		AccessToken string `json:"access_token"`
+	}{AccessToken: session.AccessToken})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("DELETE", fmt.Sprintf(githubGrantURLFormat, p.ClientID), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(p.ClientID, p.ClientSecret)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("github grant revocation failed: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (p *GithubProvider) ValidateSession(session *SessionState) bool {
+	return session != nil && session.AccessToken != ""
+}
+
+func (p *GithubProvider) GetEmailAddress(session *SessionState) (string, error) {
+	return session.Email, nil
+}
+
+func (p *GithubProvider) GetUsername(session *SessionState) (string, error) {
+	return session.User, nil
+}
@@ -0,0 +1,159 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// oauth2Config holds the pieces of a standard three-legged OAuth2
+// authorization-code flow that Google and GitHub share. It is intentionally
+// smaller than the slack.OAuthClient it sits alongside: it only builds the
+// login URL and redeems, refreshes and revokes tokens. Looking up the
+// authenticated user is left to each provider (via getJSON).
+type oauth2Config struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  *url.URL
+	AuthURL      *url.URL
+	TokenURL     *url.URL
+	RevokeURL    *url.URL
+	Scope        string
+}
+
+func (c *oauth2Config) LoginURL(state string) *url.URL {
+	params := url.Values{}
+	params.Set("client_id", c.ClientID)
+	params.Set("redirect_uri", c.RedirectURL.String())
+	params.Set("scope", c.Scope)
+	params.Set("response_type", "code")
+	params.Set("state", state)
+
+	u := *c.AuthURL
+	u.RawQuery = params.Encode()
+	return &u
+}
+
+type oauth2Token struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (c *oauth2Config) redeem(code string) (oauth2Token, error) {
+	params := url.Values{}
+	params.Set("client_id", c.ClientID)
+	params.Set("client_secret", c.ClientSecret)
+	params.Set("redirect_uri", c.RedirectURL.String())
+	params.Set("code", code)
+	params.Set("grant_type", "authorization_code")
+
+	return c.exchange(params)
+}
+
+// refresh trades a previously issued refresh token for a new access token.
+// Not every provider hands out refresh tokens (GitHub's classic OAuth apps
+// never do), so callers should surface a clear error rather than assume
+// this always succeeds.
+func (c *oauth2Config) refresh(refreshToken string) (oauth2Token, error) {
+	params := url.Values{}
+	params.Set("client_id", c.ClientID)
+	params.Set("client_secret", c.ClientSecret)
+	params.Set("refresh_token", refreshToken)
+	params.Set("grant_type", "refresh_token")
+
+	return c.exchange(params)
+}
+
+// exchange posts params as the request body, per RFC 6749 section 4.1.3 —
+// not as a query string, which would leak client_secret/codes/refresh
+// tokens into proxy and web-server access logs.
+func (c *oauth2Config) exchange(params url.Values) (oauth2Token, error) {
+	var token oauth2Token
+
+	req, err := http.NewRequest("POST", c.TokenURL.String(), strings.NewReader(params.Encode()))
+	if err != nil {
+		return token, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return token, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return token, err
+	}
+
+	if resp.StatusCode != 200 {
+		return token, fmt.Errorf("token exchange failed: %d %s", resp.StatusCode, body)
+	}
+
+	if err := json.Unmarshal(body, &token); err != nil {
+		return token, err
+	}
+
+	return token, nil
+}
+
+// revoke invalidates a token at the provider's revocation endpoint.
+// Providers that don't expose one (GitHub's classic OAuth apps) should
+// override Revoke on the embedding type instead of calling this.
+func (c *oauth2Config) revoke(token string) error {
+	if c.RevokeURL == nil {
+		return fmt.Errorf("provider has no revoke endpoint configured")
+	}
+
+	params := url.Values{}
+	params.Set("token", token)
+
+	req, err := http.NewRequest("POST", c.RevokeURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.URL.RawQuery = params.Encode()
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("token revocation failed: %d %s", resp.StatusCode, body)
+	}
+
+	return nil
+}
+
+func getJSON(url string, accessToken string, out interface{}) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("request to %s failed: %d %s", url, resp.StatusCode, body)
+	}
+
+	return json.Unmarshal(body, out)
+}
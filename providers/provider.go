@@ -0,0 +1,51 @@
+package providers
+
+import (
+	"errors"
+	"net/url"
+	"time"
+)
+
+// errNoRefreshToken is returned by Refresh implementations when the session
+// being refreshed has no refresh token to trade in, e.g. because the
+// provider never issued one or it predates this feature.
+var errNoRefreshToken = errors.New("session has no refresh token")
+
+// SessionState is the provider-agnostic result of a completed OAuth login.
+// It replaces the Slack-specific *slack.Auth that used to flow through the
+// proxy, so upstream allow-lists can match on email domain, GitHub org/team,
+// or Slack team consistently regardless of which provider authenticated the
+// request. CreatedAt is stamped when the session is first redeemed and
+// again on every refresh, so OAuthServer can tell when a cookie is due for
+// renewal without storing that state anywhere else.
+type SessionState struct {
+	User  string
+	Email string
+	Team  string
+
+	// Groups holds the org/team slugs (or equivalent) a provider's Redeem
+	// resolved for this user, e.g. GithubProvider populates it from the
+	// user's org memberships. Providers that have nothing comparable leave
+	// it nil; a Validator gating on it should fail closed in that case.
+	Groups []string
+
+	AccessToken  string
+	RefreshToken string
+	CreatedAt    time.Time
+}
+
+// Provider is implemented by every identity backend the proxy can
+// authenticate against. LoginURL builds the redirect sent to the provider,
+// Redeem exchanges an authorization code for a SessionState, and
+// ValidateSession re-checks that a session decoded from a cookie is still
+// good to use. Refresh trades a session's refresh token for a new one;
+// providers that don't issue refresh tokens should return an error.
+type Provider interface {
+	LoginURL(state string) *url.URL
+	Redeem(code string) (*SessionState, error)
+	Refresh(session *SessionState) (*SessionState, error)
+	Revoke(session *SessionState) error
+	ValidateSession(session *SessionState) bool
+	GetEmailAddress(session *SessionState) (string, error)
+	GetUsername(session *SessionState) (string, error)
+}
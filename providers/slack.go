@@ -0,0 +1,92 @@
+package providers
+
+import (
+	"github.com/tappleby/slack-auth-proxy/slack"
+	"net/url"
+	"time"
+)
+
+// SlackProvider adapts the existing slack.OAuthClient to the Provider
+// interface, preserving the original behavior for operators who don't
+// configure a different backend.
+type SlackProvider struct {
+	client *slack.OAuthClient
+}
+
+func NewSlackProvider(client *slack.OAuthClient) *SlackProvider {
+	return &SlackProvider{client: client}
+}
+
+func (p *SlackProvider) LoginURL(state string) *url.URL {
+	return p.client.LoginUrl(state)
+}
+
+func (p *SlackProvider) Redeem(code string) (*SessionState, error) {
+	access, err := p.client.RedeemCode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	cl := slack.NewClient(access.Token)
+	auth, err := cl.Auth.Test()
+	if err != nil {
+		return nil, err
+	}
+
+	return &SessionState{
+		User:         auth.Username,
+		Email:        auth.Email,
+		Team:         auth.Team,
+		AccessToken:  access.Token,
+		RefreshToken: access.RefreshToken,
+		CreatedAt:    time.Now(),
+	}, nil
+}
+
+// Refresh exchanges a session's Slack refresh token for a new access token,
+// re-validating the user against auth.test so a revoked or deactivated
+// account doesn't ride out the rest of the refresh window.
+func (p *SlackProvider) Refresh(session *SessionState) (*SessionState, error) {
+	if session.RefreshToken == "" {
+		return nil, errNoRefreshToken
+	}
+
+	access, err := p.client.RefreshToken(session.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	cl := slack.NewClient(access.Token)
+	auth, err := cl.Auth.Test()
+	if err != nil {
+		return nil, err
+	}
+
+	return &SessionState{
+		User:         auth.Username,
+		Email:        auth.Email,
+		Team:         auth.Team,
+		AccessToken:  access.Token,
+		RefreshToken: access.RefreshToken,
+		CreatedAt:    time.Now(),
+	}, nil
+}
+
+// Revoke calls Slack's auth.revoke API so a signed-out session's token can't
+// keep being used even if the cookie leaked.
+func (p *SlackProvider) Revoke(session *SessionState) error {
+	cl := slack.NewClient(session.AccessToken)
+	return cl.Auth.Revoke()
+}
+
+func (p *SlackProvider) ValidateSession(session *SessionState) bool {
+	return session != nil && session.AccessToken != ""
+}
+
+func (p *SlackProvider) GetEmailAddress(session *SessionState) (string, error) {
+	return session.Email, nil
+}
+
+func (p *SlackProvider) GetUsername(session *SessionState) (string, error) {
+	return session.User, nil
+}